@@ -0,0 +1,141 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package persistentvolume
+
+import (
+	api "k8s.io/kubernetes/pkg/apis/core"
+	"k8s.io/kubernetes/pkg/features"
+
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/component-base/featuregate"
+)
+
+// FieldGate describes a single alpha PersistentVolumeSpec field that is
+// gated behind a feature gate. DropDisabledFields clears the field from a
+// new spec when Gate is disabled and HasField reports that the old spec
+// did not already carry it, so that round-tripping and downgrades stay
+// safe without a bespoke branch per field.
+type FieldGate struct {
+	// Gate is the feature gate that guards this field.
+	Gate featuregate.Feature
+	// HasField reports whether pvSpec already carries the gated field.
+	// It must tolerate a nil pvSpec.
+	HasField func(pvSpec *api.PersistentVolumeSpec) bool
+	// ClearField clears the gated field from pvSpec.
+	ClearField func(pvSpec *api.PersistentVolumeSpec)
+}
+
+// fieldGates holds the built-in gated PersistentVolumeSpec fields, plus any
+// fields registered out-of-tree via RegisterFieldGate.
+var fieldGates = []FieldGate{
+	{
+		Gate:       features.BlockVolume,
+		HasField:   hasVolumeMode,
+		ClearField: func(pvSpec *api.PersistentVolumeSpec) { pvSpec.VolumeMode = nil },
+	},
+	{
+		Gate:       features.CSIPersistentVolume,
+		HasField:   hasCSIPersistentVolume,
+		ClearField: func(pvSpec *api.PersistentVolumeSpec) { pvSpec.PersistentVolumeSource.CSI = nil },
+	},
+	{
+		Gate:       features.PersistentLocalVolumes,
+		HasField:   hasLocalVolumeSource,
+		ClearField: func(pvSpec *api.PersistentVolumeSpec) { pvSpec.PersistentVolumeSource.Local = nil },
+	},
+	{
+		Gate:       features.PersistentVolumeSnapshotSource,
+		HasField:   hasVolumeSnapshotSource,
+		ClearField: func(pvSpec *api.PersistentVolumeSpec) { pvSpec.PersistentVolumeSource.VolumeSnapshot = nil },
+	},
+}
+
+// RegisterFieldGate adds a gated PersistentVolumeSpec field to the set
+// consulted by DropDisabledFields. It lets out-of-tree API extensions and
+// CRD-like registries participate in the same drop-disabled-fields
+// behavior as the built-in fields above without editing this package.
+//
+// RegisterFieldGate is not safe to call concurrently with DropDisabledFields
+// and is intended to be called from init().
+func RegisterFieldGate(gate FieldGate) {
+	fieldGates = append(fieldGates, gate)
+}
+
+// DropDisabledFields removes disabled fields from the pv spec.
+// This should be called from PrepareForCreate/PrepareForUpdate for all resources containing a pv spec.
+func DropDisabledFields(pvSpec *api.PersistentVolumeSpec, oldPVSpec *api.PersistentVolumeSpec) {
+	for _, gate := range fieldGates {
+		if !utilfeature.DefaultFeatureGate.Enabled(gate.Gate) && !gate.HasField(oldPVSpec) {
+			gate.ClearField(pvSpec)
+		}
+	}
+}
+
+// DropDisabledFieldsFromStatus removes disabled fields from the pv status.
+// This should be called from PrepareForCreate/PrepareForUpdate for all resources containing a pv status.
+func DropDisabledFieldsFromStatus(newPV, oldPV *api.PersistentVolume) {
+	if !utilfeature.DefaultFeatureGate.Enabled(features.VolumeAttributesClass) {
+		if !hasCurrentVolumeAttributesClassName(oldPV) {
+			newPV.Status.CurrentVolumeAttributesClassName = nil
+		}
+		if !hasModifyVolumeStatus(oldPV) {
+			newPV.Status.Conditions = nil
+		}
+	}
+}
+
+func hasVolumeMode(pvSpec *api.PersistentVolumeSpec) bool {
+	if pvSpec == nil {
+		return false
+	}
+	return pvSpec.VolumeMode != nil
+}
+
+func hasCSIPersistentVolume(pvSpec *api.PersistentVolumeSpec) bool {
+	if pvSpec == nil {
+		return false
+	}
+	return pvSpec.PersistentVolumeSource.CSI != nil
+}
+
+func hasLocalVolumeSource(pvSpec *api.PersistentVolumeSpec) bool {
+	if pvSpec == nil {
+		return false
+	}
+	return pvSpec.PersistentVolumeSource.Local != nil
+}
+
+func hasVolumeSnapshotSource(pvSpec *api.PersistentVolumeSpec) bool {
+	if pvSpec == nil {
+		return false
+	}
+	return pvSpec.PersistentVolumeSource.VolumeSnapshot != nil
+}
+
+func hasCurrentVolumeAttributesClassName(pv *api.PersistentVolume) bool {
+	if pv == nil {
+		return false
+	}
+	return pv.Status.CurrentVolumeAttributesClassName != nil
+}
+
+func hasModifyVolumeStatus(pv *api.PersistentVolume) bool {
+	if pv == nil {
+		return false
+	}
+	return len(pv.Status.Conditions) > 0
+}
@@ -17,7 +17,6 @@ limitations under the License.
 package persistentvolume
 
 import (
-	"fmt"
 	"reflect"
 	"testing"
 
@@ -26,226 +25,255 @@ import (
 	utilfeaturetesting "k8s.io/apiserver/pkg/util/feature/testing"
 	api "k8s.io/kubernetes/pkg/apis/core"
 	"k8s.io/kubernetes/pkg/features"
+
+	"k8s.io/component-base/featuregate"
 )
 
 func TestDropDisabledFields(t *testing.T) {
-	specWithCSI := func() *api.PersistentVolumeSpec {
-		return &api.PersistentVolumeSpec{PersistentVolumeSource: api.PersistentVolumeSource{CSI: &api.CSIPersistentVolumeSource{}}}
-	}
-	specWithoutCSI := func() *api.PersistentVolumeSpec {
-		return &api.PersistentVolumeSpec{PersistentVolumeSource: api.PersistentVolumeSource{CSI: nil}}
-	}
-	specWithMode := func(mode *api.PersistentVolumeMode) *api.PersistentVolumeSpec {
-		return &api.PersistentVolumeSpec{VolumeMode: mode}
-	}
-
 	modeBlock := api.PersistentVolumeBlock
+	localFSType := "ext4"
 
-	tests := map[string]struct {
-		oldSpec       *api.PersistentVolumeSpec
-		newSpec       *api.PersistentVolumeSpec
-		expectOldSpec *api.PersistentVolumeSpec
-		expectNewSpec *api.PersistentVolumeSpec
-		csiEnabled    bool
-		blockEnabled  bool
+	gateCases := []struct {
+		name         string
+		gate         featuregate.Feature
+		withField    func() *api.PersistentVolumeSpec
+		withoutField func() *api.PersistentVolumeSpec
 	}{
-		"disabled csi clears new": {
-			csiEnabled:    false,
-			newSpec:       specWithCSI(),
-			expectNewSpec: specWithoutCSI(),
-			oldSpec:       nil,
-			expectOldSpec: nil,
-		},
-		"disabled csi clears update when old pv did not use csi": {
-			csiEnabled:    false,
-			newSpec:       specWithCSI(),
-			expectNewSpec: specWithoutCSI(),
-			oldSpec:       specWithoutCSI(),
-			expectOldSpec: specWithoutCSI(),
-		},
-		"disabled csi preserves update when old pv did use csi": {
-			csiEnabled:    false,
-			newSpec:       specWithCSI(),
-			expectNewSpec: specWithCSI(),
-			oldSpec:       specWithCSI(),
-			expectOldSpec: specWithCSI(),
+		{
+			name: "csi",
+			gate: features.CSIPersistentVolume,
+			withField: func() *api.PersistentVolumeSpec {
+				return &api.PersistentVolumeSpec{PersistentVolumeSource: api.PersistentVolumeSource{CSI: &api.CSIPersistentVolumeSource{}}}
+			},
+			withoutField: func() *api.PersistentVolumeSpec {
+				return &api.PersistentVolumeSpec{PersistentVolumeSource: api.PersistentVolumeSource{CSI: nil}}
+			},
 		},
-
-		"enabled csi preserves new": {
-			csiEnabled:    true,
-			newSpec:       specWithCSI(),
-			expectNewSpec: specWithCSI(),
-			oldSpec:       nil,
-			expectOldSpec: nil,
+		{
+			name: "block volume mode",
+			gate: features.BlockVolume,
+			withField: func() *api.PersistentVolumeSpec {
+				return &api.PersistentVolumeSpec{VolumeMode: &modeBlock}
+			},
+			withoutField: func() *api.PersistentVolumeSpec {
+				return &api.PersistentVolumeSpec{VolumeMode: nil}
+			},
 		},
-		"enabled csi preserves update when old pv did not use csi": {
-			csiEnabled:    true,
-			newSpec:       specWithCSI(),
-			expectNewSpec: specWithCSI(),
-			oldSpec:       specWithoutCSI(),
-			expectOldSpec: specWithoutCSI(),
+		{
+			name: "local volume",
+			gate: features.PersistentLocalVolumes,
+			withField: func() *api.PersistentVolumeSpec {
+				return &api.PersistentVolumeSpec{PersistentVolumeSource: api.PersistentVolumeSource{Local: &api.LocalVolumeSource{Path: "/a/b/c", FSType: &localFSType}}}
+			},
+			withoutField: func() *api.PersistentVolumeSpec {
+				return &api.PersistentVolumeSpec{PersistentVolumeSource: api.PersistentVolumeSource{Local: nil}}
+			},
 		},
-		"enabled csi preserves update when old pv did use csi": {
-			csiEnabled:    true,
-			newSpec:       specWithCSI(),
-			expectNewSpec: specWithCSI(),
-			oldSpec:       specWithCSI(),
-			expectOldSpec: specWithCSI(),
+		{
+			name: "volume snapshot source",
+			gate: features.PersistentVolumeSnapshotSource,
+			withField: func() *api.PersistentVolumeSpec {
+				return &api.PersistentVolumeSpec{PersistentVolumeSource: api.PersistentVolumeSource{VolumeSnapshot: &api.VolumeSnapshotPersistentVolumeSource{Name: "snap-1"}}}
+			},
+			withoutField: func() *api.PersistentVolumeSpec {
+				return &api.PersistentVolumeSpec{PersistentVolumeSource: api.PersistentVolumeSource{VolumeSnapshot: nil}}
+			},
 		},
+	}
 
-		"disabled block clears new": {
-			blockEnabled:  false,
-			newSpec:       specWithMode(&modeBlock),
-			expectNewSpec: specWithMode(nil),
-			oldSpec:       nil,
-			expectOldSpec: nil,
-		},
-		"disabled block clears update when old pv did not use block": {
-			blockEnabled:  false,
-			newSpec:       specWithMode(&modeBlock),
-			expectNewSpec: specWithMode(nil),
-			oldSpec:       specWithMode(nil),
-			expectOldSpec: specWithMode(nil),
-		},
-		"disabled block does not clear new on update when old pv did use block": {
-			blockEnabled:  false,
-			newSpec:       specWithMode(&modeBlock),
-			expectNewSpec: specWithMode(&modeBlock),
-			oldSpec:       specWithMode(&modeBlock),
-			expectOldSpec: specWithMode(&modeBlock),
-		},
+	// Each registered gate is put through the same disabled/enabled x
+	// old-had-it/old-lacked-it/old-nil matrix, so adding a new entry to
+	// gateCases is all a future gated field needs.
+	for _, gc := range gateCases {
+		t.Run(gc.name, func(t *testing.T) {
+			scenarios := map[string]struct {
+				enabled       bool
+				oldSpec       *api.PersistentVolumeSpec
+				expectCleared bool
+			}{
+				"disabled clears new on create": {
+					enabled:       false,
+					oldSpec:       nil,
+					expectCleared: true,
+				},
+				"disabled clears update when old spec lacked field": {
+					enabled:       false,
+					oldSpec:       gc.withoutField(),
+					expectCleared: true,
+				},
+				"disabled preserves update when old spec had field": {
+					enabled:       false,
+					oldSpec:       gc.withField(),
+					expectCleared: false,
+				},
+				"enabled preserves new on create": {
+					enabled:       true,
+					oldSpec:       nil,
+					expectCleared: false,
+				},
+				"enabled preserves update when old spec lacked field": {
+					enabled:       true,
+					oldSpec:       gc.withoutField(),
+					expectCleared: false,
+				},
+				"enabled preserves update when old spec had field": {
+					enabled:       true,
+					oldSpec:       gc.withField(),
+					expectCleared: false,
+				},
+			}
 
-		"enabled block preserves new": {
-			blockEnabled:  true,
-			newSpec:       specWithMode(&modeBlock),
-			expectNewSpec: specWithMode(&modeBlock),
-			oldSpec:       nil,
-			expectOldSpec: nil,
-		},
-		"enabled block preserves update when old pv did not use block": {
-			blockEnabled:  true,
-			newSpec:       specWithMode(&modeBlock),
-			expectNewSpec: specWithMode(&modeBlock),
-			oldSpec:       specWithMode(nil),
-			expectOldSpec: specWithMode(nil),
-		},
-		"enabled block preserves update when old pv did use block": {
-			blockEnabled:  true,
-			newSpec:       specWithMode(&modeBlock),
-			expectNewSpec: specWithMode(&modeBlock),
-			oldSpec:       specWithMode(&modeBlock),
-			expectOldSpec: specWithMode(&modeBlock),
-		},
-	}
+			for name, s := range scenarios {
+				t.Run(name, func(t *testing.T) {
+					defer utilfeaturetesting.SetFeatureGateDuringTest(t, utilfeature.DefaultFeatureGate, gc.gate, s.enabled)()
 
-	for name, tc := range tests {
-		t.Run(name, func(t *testing.T) {
-			defer utilfeaturetesting.SetFeatureGateDuringTest(t, utilfeature.DefaultFeatureGate, features.CSIPersistentVolume, tc.csiEnabled)()
-			defer utilfeaturetesting.SetFeatureGateDuringTest(t, utilfeature.DefaultFeatureGate, features.BlockVolume, tc.blockEnabled)()
+					newSpec := gc.withField()
+					var wantOldSpec *api.PersistentVolumeSpec
+					if s.oldSpec != nil {
+						oldCopy := *s.oldSpec
+						wantOldSpec = &oldCopy
+					}
 
-			DropDisabledFields(tc.newSpec, tc.oldSpec)
-			if !reflect.DeepEqual(tc.newSpec, tc.expectNewSpec) {
-				t.Error(diff.ObjectReflectDiff(tc.newSpec, tc.expectNewSpec))
-			}
-			if !reflect.DeepEqual(tc.oldSpec, tc.expectOldSpec) {
-				t.Error(diff.ObjectReflectDiff(tc.oldSpec, tc.expectOldSpec))
+					DropDisabledFields(newSpec, s.oldSpec)
+
+					wantNewSpec := gc.withField()
+					if s.expectCleared {
+						wantNewSpec = gc.withoutField()
+					}
+					if !reflect.DeepEqual(newSpec, wantNewSpec) {
+						t.Error(diff.ObjectReflectDiff(newSpec, wantNewSpec))
+					}
+					if !reflect.DeepEqual(s.oldSpec, wantOldSpec) {
+						t.Error(diff.ObjectReflectDiff(s.oldSpec, wantOldSpec))
+					}
+				})
 			}
 		})
 	}
 }
 
-func TestDropDisabledFieldsPersistentLocalVolume(t *testing.T) {
-	pvWithoutLocalVolume := func() *api.PersistentVolume {
-		return &api.PersistentVolume{
-			Spec: api.PersistentVolumeSpec{
-				PersistentVolumeSource: api.PersistentVolumeSource{
-					Local: nil,
-				},
-			},
-		}
+func TestRegisterFieldGate(t *testing.T) {
+	// Reuse an existing, already-registered feature gate rather than an
+	// invented one, since feature gates must be registered before a test
+	// can flip them.
+	fakeGate := features.VolumeAttributesClass
+	saved := fieldGates
+	defer func() { fieldGates = saved }()
+
+	var cleared bool
+	RegisterFieldGate(FieldGate{
+		Gate:       fakeGate,
+		HasField:   func(pvSpec *api.PersistentVolumeSpec) bool { return false },
+		ClearField: func(pvSpec *api.PersistentVolumeSpec) { cleared = true },
+	})
+
+	if len(fieldGates) != len(saved)+1 {
+		t.Fatalf("got %d field gates, want %d", len(fieldGates), len(saved)+1)
 	}
-	pvWithLocalVolume := func() *api.PersistentVolume {
-		fsType := "ext4"
-		return &api.PersistentVolume{
-			Spec: api.PersistentVolumeSpec{
-				PersistentVolumeSource: api.PersistentVolumeSource{
-					Local: &api.LocalVolumeSource{
-						Path:   "/a/b/c",
-						FSType: &fsType,
-					},
-				},
-			},
-		}
+
+	defer utilfeaturetesting.SetFeatureGateDuringTest(t, utilfeature.DefaultFeatureGate, fakeGate, false)()
+	DropDisabledFields(&api.PersistentVolumeSpec{}, nil)
+
+	if !cleared {
+		t.Error("DropDisabledFields did not consult the registered field gate")
+	}
+}
+
+func TestDropDisabledFieldsFromStatus(t *testing.T) {
+	className := "class-1"
+
+	pvWithCurrentVACName := func() *api.PersistentVolume {
+		return &api.PersistentVolume{Status: api.PersistentVolumeStatus{CurrentVolumeAttributesClassName: &className}}
+	}
+	pvWithoutCurrentVACName := func() *api.PersistentVolume {
+		return &api.PersistentVolume{Status: api.PersistentVolumeStatus{CurrentVolumeAttributesClassName: nil}}
+	}
+	pvWithModifyingCondition := func() *api.PersistentVolume {
+		return &api.PersistentVolume{Status: api.PersistentVolumeStatus{Conditions: []api.PersistentVolumeCondition{
+			{Type: api.PersistentVolumeModifyingVolume},
+		}}}
+	}
+	pvWithoutModifyingCondition := func() *api.PersistentVolume {
+		return &api.PersistentVolume{Status: api.PersistentVolumeStatus{Conditions: nil}}
 	}
 
-	pvInfo := []struct {
-		description    string
-		hasLocalVolume bool
-		pv             func() *api.PersistentVolume
+	tests := map[string]struct {
+		oldPV       *api.PersistentVolume
+		newPV       *api.PersistentVolume
+		expectOldPV *api.PersistentVolume
+		expectNewPV *api.PersistentVolume
+		vacEnabled  bool
 	}{
-		{
-			description:    "pv without LocalVolume",
-			hasLocalVolume: false,
-			pv:             pvWithoutLocalVolume,
+		"disabled vac clears new": {
+			vacEnabled:  false,
+			newPV:       pvWithCurrentVACName(),
+			expectNewPV: pvWithoutCurrentVACName(),
+			oldPV:       nil,
+			expectOldPV: nil,
 		},
-		{
-			description:    "pv with LocalVolume",
-			hasLocalVolume: true,
-			pv:             pvWithLocalVolume,
+		"disabled vac clears update when old pv did not use vac": {
+			vacEnabled:  false,
+			newPV:       pvWithCurrentVACName(),
+			expectNewPV: pvWithoutCurrentVACName(),
+			oldPV:       pvWithoutCurrentVACName(),
+			expectOldPV: pvWithoutCurrentVACName(),
 		},
-		{
-			description:    "is nil",
-			hasLocalVolume: false,
-			pv:             func() *api.PersistentVolume { return nil },
+		"disabled vac preserves update when old pv did use vac": {
+			vacEnabled:  false,
+			newPV:       pvWithCurrentVACName(),
+			expectNewPV: pvWithCurrentVACName(),
+			oldPV:       pvWithCurrentVACName(),
+			expectOldPV: pvWithCurrentVACName(),
+		},
+		"enabled vac preserves new": {
+			vacEnabled:  true,
+			newPV:       pvWithCurrentVACName(),
+			expectNewPV: pvWithCurrentVACName(),
+			oldPV:       nil,
+			expectOldPV: nil,
 		},
-	}
 
-	for _, enabled := range []bool{true, false} {
-		for _, oldpvInfo := range pvInfo {
-			for _, newpvInfo := range pvInfo {
-				oldpvHasLocalVolume, oldpv := oldpvInfo.hasLocalVolume, oldpvInfo.pv()
-				newpvHasLocalVolume, newpv := newpvInfo.hasLocalVolume, newpvInfo.pv()
-				if newpv == nil {
-					continue
-				}
-
-				t.Run(fmt.Sprintf("feature enabled=%v, old pvc %v, new pvc %v", enabled, oldpvInfo.description, newpvInfo.description), func(t *testing.T) {
-					defer utilfeaturetesting.SetFeatureGateDuringTest(t, utilfeature.DefaultFeatureGate, features.PersistentLocalVolumes, enabled)()
-
-					var oldpvSpec *api.PersistentVolumeSpec
-					if oldpv != nil {
-						oldpvSpec = &oldpv.Spec
-					}
-					DropDisabledFields(&newpv.Spec, oldpvSpec)
+		"disabled vac clears modify-in-progress condition on new": {
+			vacEnabled:  false,
+			newPV:       pvWithModifyingCondition(),
+			expectNewPV: pvWithoutModifyingCondition(),
+			oldPV:       nil,
+			expectOldPV: nil,
+		},
+		"disabled vac clears modify-in-progress condition when old pv did not have it": {
+			vacEnabled:  false,
+			newPV:       pvWithModifyingCondition(),
+			expectNewPV: pvWithoutModifyingCondition(),
+			oldPV:       pvWithoutModifyingCondition(),
+			expectOldPV: pvWithoutModifyingCondition(),
+		},
+		"disabled vac preserves modify-in-progress condition when old pv already had it": {
+			vacEnabled:  false,
+			newPV:       pvWithModifyingCondition(),
+			expectNewPV: pvWithModifyingCondition(),
+			oldPV:       pvWithModifyingCondition(),
+			expectOldPV: pvWithModifyingCondition(),
+		},
+		"enabled vac preserves modify-in-progress condition": {
+			vacEnabled:  true,
+			newPV:       pvWithModifyingCondition(),
+			expectNewPV: pvWithModifyingCondition(),
+			oldPV:       nil,
+			expectOldPV: nil,
+		},
+	}
 
-					// old pv should never be changed
-					if !reflect.DeepEqual(oldpv, oldpvInfo.pv()) {
-						t.Errorf("old pv changed: %v", diff.ObjectReflectDiff(oldpv, oldpvInfo.pv()))
-					}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer utilfeaturetesting.SetFeatureGateDuringTest(t, utilfeature.DefaultFeatureGate, features.VolumeAttributesClass, tc.vacEnabled)()
 
-					switch {
-					case enabled || oldpvHasLocalVolume:
-						// new pv should not be changed if the feature is enabled, or if the old pv had LocalVolume source
-						if !reflect.DeepEqual(newpv, newpvInfo.pv()) {
-							t.Errorf("new pv changed: %v", diff.ObjectReflectDiff(newpv, newpvInfo.pv()))
-						}
-					case newpvHasLocalVolume:
-						// new pv should be changed
-						if reflect.DeepEqual(newpv, newpvInfo.pv()) {
-							t.Errorf("new pv was not changed")
-						}
-						// new pv should not have LocalVolume
-						if !reflect.DeepEqual(newpv, pvWithoutLocalVolume()) {
-							t.Errorf("new pv had LocalVolume source: %v", diff.ObjectReflectDiff(newpv, pvWithoutLocalVolume()))
-						}
-					default:
-						// new pv should not need to be changed
-						if !reflect.DeepEqual(newpv, newpvInfo.pv()) {
-							t.Errorf("new pv changed: %v", diff.ObjectReflectDiff(newpv, newpvInfo.pv()))
-						}
-					}
-				})
+			DropDisabledFieldsFromStatus(tc.newPV, tc.oldPV)
+			if !reflect.DeepEqual(tc.newPV, tc.expectNewPV) {
+				t.Error(diff.ObjectReflectDiff(tc.newPV, tc.expectNewPV))
 			}
-		}
+			if !reflect.DeepEqual(tc.oldPV, tc.expectOldPV) {
+				t.Error(diff.ObjectReflectDiff(tc.oldPV, tc.expectOldPV))
+			}
+		})
 	}
 }
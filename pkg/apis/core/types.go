@@ -0,0 +1,149 @@
+/*
+Copyright 2014 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PersistentVolume (PV) is a storage resource provisioned by an administrator.
+// It is analogous to a node.
+type PersistentVolume struct {
+	metav1.TypeMeta
+	metav1.ObjectMeta
+
+	// spec defines a specification of a persistent volume owned by the cluster.
+	Spec PersistentVolumeSpec
+
+	// status represents the current information about a persistent volume.
+	Status PersistentVolumeStatus
+}
+
+// PersistentVolumeSpec has most of the details required to define a persistent volume.
+type PersistentVolumeSpec struct {
+	// persistentVolumeSource is the actual volume backing the persistent volume.
+	PersistentVolumeSource
+
+	// volumeMode defines if a volume is intended to be used with a formatted
+	// filesystem or to remain in raw block state.
+	VolumeMode *PersistentVolumeMode
+}
+
+// PersistentVolumeStatus is the current status of a persistent volume.
+type PersistentVolumeStatus struct {
+	// currentVolumeAttributesClassName is the current name of the
+	// VolumeAttributesClass the PV is using, if any.
+	CurrentVolumeAttributesClassName *string
+
+	// conditions is the set of conditions describing the current state of a
+	// PV's modification, such as an in-progress VolumeAttributesClass change.
+	Conditions []PersistentVolumeCondition
+}
+
+// PersistentVolumeConditionType is a valid value of PersistentVolumeCondition.Type.
+type PersistentVolumeConditionType string
+
+// These are valid conditions of a persistent volume.
+const (
+	// PersistentVolumeModifyingVolume indicates that a volume modification
+	// (such as a VolumeAttributesClass change) is in progress.
+	PersistentVolumeModifyingVolume PersistentVolumeConditionType = "ModifyingVolume"
+)
+
+// PersistentVolumeCondition describes the state of a persistent volume at a
+// certain point in time.
+type PersistentVolumeCondition struct {
+	// type is the type of the condition.
+	Type PersistentVolumeConditionType
+	// status is the status of the condition.
+	Status ConditionStatus
+	// message is a human-readable message indicating details about the
+	// last transition.
+	// +optional
+	Message string
+	// reason is a brief machine readable explanation for the condition's
+	// last transition.
+	// +optional
+	Reason string
+}
+
+// ConditionStatus is the status of a condition, mirroring the common
+// True/False/Unknown tri-state used across the API.
+type ConditionStatus string
+
+// PersistentVolumeMode describes how a volume is intended to be consumed,
+// either Block or Filesystem.
+type PersistentVolumeMode string
+
+const (
+	// PersistentVolumeBlock means the volume will not be formatted with a
+	// filesystem and will remain a raw block device.
+	PersistentVolumeBlock PersistentVolumeMode = "Block"
+	// PersistentVolumeFilesystem means the volume will be or is formatted
+	// with a filesystem.
+	PersistentVolumeFilesystem PersistentVolumeMode = "Filesystem"
+)
+
+// PersistentVolumeSource is similar to VolumeSource but meant for the
+// administrator who creates PVs, not the fields that should be used by the
+// developer who creates a PVC.
+type PersistentVolumeSource struct {
+	// csi represents storage that is handled by an external CSI driver.
+	// +optional
+	CSI *CSIPersistentVolumeSource
+	// local represents directly-attached storage on the node.
+	// +optional
+	Local *LocalVolumeSource
+	// volumeSnapshot, if set, restores this volume directly from a
+	// pre-existing CSI VolumeSnapshot instead of provisioning an empty volume.
+	// +optional
+	VolumeSnapshot *VolumeSnapshotPersistentVolumeSource
+}
+
+// CSIPersistentVolumeSource represents storage that is handled by an
+// external CSI driver.
+type CSIPersistentVolumeSource struct {
+	// driver is the name of the driver to use for this volume.
+	Driver string
+	// volumeHandle is the unique volume name returned by the CSI driver.
+	VolumeHandle string
+}
+
+// LocalVolumeSource represents directly-attached storage on a node.
+type LocalVolumeSource struct {
+	// path is the full path to the volume on the node.
+	Path string
+	// fsType is the filesystem type to mount.
+	// +optional
+	FSType *string
+}
+
+// VolumeSnapshotPersistentVolumeSource represents a pre-existing CSI
+// VolumeSnapshot that a PersistentVolume should be restored from.
+type VolumeSnapshotPersistentVolumeSource struct {
+	// name is the name of the VolumeSnapshot in the given namespace that
+	// this volume should be restored from.
+	Name string
+	// namespace is the namespace of the VolumeSnapshot. It is required
+	// because VolumeSnapshot is namespaced while PersistentVolume is not.
+	Namespace string
+	// volumeSnapshotContentName, if set, pins the restore to a specific
+	// pre-provisioned VolumeSnapshotContent instead of resolving the
+	// VolumeSnapshot's bound content dynamically.
+	// +optional
+	VolumeSnapshotContentName *string
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2014 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package persistentvolume
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/storage/names"
+	"k8s.io/kubernetes/pkg/api/legacyscheme"
+	pvutil "k8s.io/kubernetes/pkg/api/persistentvolume"
+	api "k8s.io/kubernetes/pkg/apis/core"
+)
+
+// persistentvolumeStrategy implements behavior for PersistentVolume objects
+type persistentvolumeStrategy struct {
+	runtime.ObjectTyper
+	names.NameGenerator
+}
+
+// Strategy is the default logic that applies when creating and updating
+// PersistentVolume objects via the REST API.
+var Strategy = persistentvolumeStrategy{legacyscheme.Scheme, names.SimpleNameGenerator}
+
+func (persistentvolumeStrategy) NamespaceScoped() bool {
+	return false
+}
+
+// PrepareForCreate clears the Status and drops any disabled spec fields
+// before a new PersistentVolume is persisted.
+func (persistentvolumeStrategy) PrepareForCreate(ctx context.Context, obj runtime.Object) {
+	pv := obj.(*api.PersistentVolume)
+	pv.Status = api.PersistentVolumeStatus{}
+
+	pvutil.DropDisabledFields(&pv.Spec, nil)
+}
+
+// PrepareForUpdate preserves Status across a spec update and drops any
+// disabled spec fields that the old object did not already carry.
+func (persistentvolumeStrategy) PrepareForUpdate(ctx context.Context, obj, old runtime.Object) {
+	newPv := obj.(*api.PersistentVolume)
+	oldPv := old.(*api.PersistentVolume)
+	newPv.Status = oldPv.Status
+
+	pvutil.DropDisabledFields(&newPv.Spec, &oldPv.Spec)
+}
+
+// persistentvolumeStatusStrategy implements behavior for updating only the
+// status subresource of a PersistentVolume.
+type persistentvolumeStatusStrategy struct {
+	persistentvolumeStrategy
+}
+
+// StatusStrategy is the default logic that applies when updating the status
+// subresource of a PersistentVolume via the REST API.
+var StatusStrategy = persistentvolumeStatusStrategy{Strategy}
+
+// PrepareForUpdate preserves Spec across a status update and drops any
+// disabled status fields that the old object did not already carry.
+func (persistentvolumeStatusStrategy) PrepareForUpdate(ctx context.Context, obj, old runtime.Object) {
+	newPv := obj.(*api.PersistentVolume)
+	oldPv := old.(*api.PersistentVolume)
+	newPv.Spec = oldPv.Spec
+
+	pvutil.DropDisabledFieldsFromStatus(newPv, oldPv)
+}